@@ -2,10 +2,16 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/blinklabs-io/gouroboros/ledger"
 	"github.com/blinklabs-io/gouroboros/protocol/chainsync"
@@ -31,13 +37,315 @@ type BlockIndexerConfig struct {
 	KeepAllTxOutputsInDb bool `json:"keepAllTxOutputsInDb"`
 
 	AddressCheck int `json:"addressCheck"`
+
+	// how many already confirmed blocks we are allowed to disconnect on a deep reorg
+	// before giving up and returning errBlockSyncerFatal. Zero (the default, and what any
+	// deployment predating this field gets on upgrade) disables deep reorg handling
+	// entirely, matching the old behavior of treating any rollback past the latest
+	// confirmed block as immediately fatal.
+	MaxRollbackDepth uint `json:"maxRollbackDepth"`
+
+	// BuildAddressIndex enables the address -> tx reverse lookup index. Leave it off
+	// if GetTxsByAddress/GetAddressBalance are not needed, since it adds write
+	// amplification to every confirmed block.
+	BuildAddressIndex bool `json:"buildAddressIndex"`
+
+	// how many queued confirmed blocks the notification outbox delivers to the
+	// ConfirmedBlockNotifier per Notify call
+	NotifyBatchSize int `json:"notifyBatchSize"`
+
+	// how often the notification outbox polls pending_notifications for new rows
+	NotifyFlushInterval time.Duration `json:"notifyFlushInterval"`
+}
+
+// AddressTxDirection distinguishes whether an address was involved as a tx input or output
+type AddressTxDirection int
+
+const (
+	AddressTxDirectionInput AddressTxDirection = iota
+	AddressTxDirectionOutput
+)
+
+// AddressTxRef is a single reverse-lookup entry written for an address touched by a
+// confirmed transaction, used to answer GetTxsByAddress without scanning every block
+type AddressTxRef struct {
+	Address   string
+	BlockSlot uint64
+	BlockHash []byte
+	TxHash    string
+	Direction AddressTxDirection
+	// Amount is the value of the tx output this entry refers to, regardless of
+	// whether the address was involved as that output's recipient or its spender
+	Amount uint64
 }
 
 type NewConfirmedBlockHandler func(*FullBlock) error
 
-type blockWithLazyTxRetriever struct {
+// ConfirmedBlockNotifier delivers batches of newly confirmed blocks drained from the
+// pending_notifications outbox. Notify should return an error for the whole batch to be
+// retried with backoff; the outbox only removes rows once Notify succeeds, so a crash
+// between commit and a successful Notify simply redelivers the batch on restart.
+type ConfirmedBlockNotifier interface {
+	Notify(ctx context.Context, blocks []*FullBlock) error
+}
+
+// CallbackConfirmedBlockNotifier adapts the original in-process NewConfirmedBlockHandler
+// callback to the ConfirmedBlockNotifier interface, invoking it once per block in the batch
+// and stopping at the first error.
+type CallbackConfirmedBlockNotifier struct {
+	Handler NewConfirmedBlockHandler
+}
+
+func (n *CallbackConfirmedBlockNotifier) Notify(ctx context.Context, blocks []*FullBlock) error {
+	for _, block := range blocks {
+		if err := n.Handler(block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WebhookConfirmedBlockNotifier posts each batch as a JSON array to a configured HTTP
+// endpoint, treating any non-2xx response or transport error as a failed delivery.
+type WebhookConfirmedBlockNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookConfirmedBlockNotifier(url string) *WebhookConfirmedBlockNotifier {
+	return &WebhookConfirmedBlockNotifier{
+		URL:    url,
+		Client: http.DefaultClient,
+	}
+}
+
+func (n *WebhookConfirmedBlockNotifier) Notify(ctx context.Context, blocks []*FullBlock) error {
+	payload, err := json.Marshal(blocks)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook notifier: unexpected status code %d from %s", resp.StatusCode, n.URL)
+	}
+
+	return nil
+}
+
+// RolledBackBlockHandler is invoked for every confirmed block that gets disconnected
+// while replaying a deep reorg in RollbackConfirmedBlock
+type RolledBackBlockHandler func(*BlockPoint) error
+
+// BlockUndo is the journal written alongside a confirmed block so that it can later be
+// disconnected: it restores the tx outputs spent by the block and removes the ones it added
+type BlockUndo struct {
+	BlockSlot uint64
+	BlockHash []byte
+
+	// the block this one replaced as latestBlockPoint right before it was confirmed
+	PrevBlockPoint *BlockPoint
+
+	// tx outputs that this block removed from the UTXO set -- restored on disconnect
+	RemovedTxOutputs []*TxInputOutput
+	// tx inputs that this block added to the UTXO set -- deleted on disconnect
+	AddedTxOutputs []*TxInput
+
+	// address -> tx reverse lookup entries this block added -- removed on disconnect
+	AddedAddressTxRefs []*AddressTxRef
+}
+
+// blockNode is a single unconfirmed block tracked by BlockIndex. parent is nil when the node
+// attaches directly to the latest confirmed block.
+type blockNode struct {
 	header *BlockHeader
 	getTxs GetTxsFunc
+	parent *blockNode
+}
+
+// depth is the number of blocks from the latest confirmed block down to and including n
+func (n *blockNode) depth() uint {
+	d := uint(1)
+	for p := n.parent; p != nil; p = p.parent {
+		d++
+	}
+
+	return d
+}
+
+func blockHashKey(hash []byte) string {
+	return string(hash)
+}
+
+// BlockIndex is a tree of unconfirmed blocks keyed by block hash, rooted at the latest
+// confirmed block. It replaces a flat slice of unconfirmed blocks so that competing chain
+// tips of similar depth -- common while Ouroboros is settling on a winner -- can both be
+// tracked instead of one side silently losing work or the syncer bailing out fatally.
+type BlockIndex struct {
+	nodesByHash map[string]*blockNode
+	tips        map[string]*blockNode
+}
+
+func newBlockIndex() *BlockIndex {
+	return &BlockIndex{
+		nodesByHash: make(map[string]*blockNode),
+		tips:        make(map[string]*blockNode),
+	}
+}
+
+// add attaches header as a new tip, parented to the node matching header.PrevHash if one is
+// being tracked, or directly to the latest confirmed block otherwise
+func (idx *BlockIndex) add(header *BlockHeader, getTxs GetTxsFunc) *blockNode {
+	node := &blockNode{
+		header: header,
+		getTxs: getTxs,
+		parent: idx.nodesByHash[blockHashKey(header.PrevHash)],
+	}
+
+	if node.parent != nil {
+		delete(idx.tips, blockHashKey(node.parent.header.BlockHash))
+	}
+
+	key := blockHashKey(header.BlockHash)
+	idx.nodesByHash[key] = node
+	idx.tips[key] = node
+
+	return node
+}
+
+// tipLess orders two tips deterministically: deepest first, then lowest block hash. Map
+// iteration order is randomized, so without this tie-break, two tips at equal depth would
+// make bestTip's choice (and thus which branch gets promoted) flap across calls for no
+// chain-related reason.
+func tipLess(a, b *blockNode) bool {
+	if da, db := a.depth(), b.depth(); da != db {
+		return da > db
+	}
+
+	return bytes.Compare(a.header.BlockHash, b.header.BlockHash) < 0
+}
+
+// bestTip returns the tip of the deepest tracked branch, or nil if the index is empty. Ties
+// at equal depth are broken deterministically by tipLess.
+func (idx *BlockIndex) bestTip() *blockNode {
+	var best *blockNode
+
+	for _, tip := range idx.tips {
+		if best == nil || tipLess(tip, best) {
+			best = tip
+		}
+	}
+
+	return best
+}
+
+// branchesAtDepth returns the tip of every tracked branch that is at least depth blocks long,
+// ordered deterministically by tipLess
+func (idx *BlockIndex) branchesAtDepth(depth uint) (res []*blockNode) {
+	for _, tip := range idx.tips {
+		if tip.depth() >= depth {
+			res = append(res, tip)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return tipLess(res[i], res[j])
+	})
+
+	return res
+}
+
+// selectBranch keeps only the branch ending at (slot, hash), discarding every competing fork.
+// Used by RollBackwardFunc when the chainsync client wants to resume extending from a block
+// that is still unconfirmed.
+func (idx *BlockIndex) selectBranch(slot uint64, hash []byte) (*blockNode, bool) {
+	node, ok := idx.nodesByHash[blockHashKey(hash)]
+	if !ok || node.header.BlockSlot != slot {
+		return nil, false
+	}
+
+	kept := make(map[string]bool, node.depth())
+	for p := node; p != nil; p = p.parent {
+		kept[blockHashKey(p.header.BlockHash)] = true
+	}
+
+	for key := range idx.nodesByHash {
+		if !kept[key] {
+			delete(idx.nodesByHash, key)
+		}
+	}
+
+	idx.tips = map[string]*blockNode{blockHashKey(hash): node}
+
+	return node, true
+}
+
+// promote detaches the oldest node of branch (the one parented directly to the latest
+// confirmed block) so the caller can process it as newly confirmed, then prunes every branch
+// that did not descend from it, since that history forked before the new confirmed tip.
+func (idx *BlockIndex) promote(branch *blockNode) *blockNode {
+	oldest := branch
+	for oldest.parent != nil {
+		oldest = oldest.parent
+	}
+
+	delete(idx.nodesByHash, blockHashKey(oldest.header.BlockHash))
+	delete(idx.tips, blockHashKey(oldest.header.BlockHash))
+
+	var detached []*blockNode
+
+	for key, node := range idx.nodesByHash {
+		anchored := false
+
+		for p := node; p != nil; p = p.parent {
+			if p == oldest {
+				anchored = true
+
+				break
+			}
+		}
+
+		if !anchored {
+			delete(idx.nodesByHash, key)
+			delete(idx.tips, key)
+		} else if node.parent == oldest {
+			detached = append(detached, node)
+		}
+	}
+
+	// reparent only after the walk above, so every descendant still reaches oldest through
+	// its ancestors whatever the map iteration order
+	for _, node := range detached {
+		node.parent = nil
+	}
+
+	return oldest
+}
+
+// reset discards every tracked unconfirmed block, used when rolling back to the latest
+// confirmed block itself
+func (idx *BlockIndex) reset() {
+	idx.nodesByHash = make(map[string]*blockNode)
+	idx.tips = make(map[string]*blockNode)
 }
 
 type BlockIndexer struct {
@@ -46,18 +354,52 @@ type BlockIndexer struct {
 	// latest confirmed and saved block point
 	latestBlockPoint *BlockPoint
 
-	newConfirmedBlockHandler NewConfirmedBlockHandler
-	unconfirmedBlocks        []blockWithLazyTxRetriever
+	notifier               ConfirmedBlockNotifier
+	rolledBackBlockHandler RolledBackBlockHandler
+	unconfirmedBlocks      *BlockIndex
 
 	db                  BlockIndexerDb
 	addressesOfInterest map[string]bool
 
+	// mu guards latestBlockPoint, unconfirmedBlocks, tipSlot and tipBlock: they are mutated by
+	// RollForwardFunc/RollBackwardFunc on the chainsync goroutine, and read by SyncProgress,
+	// BestUnconfirmedTip, BranchesAtDepth and NextBlockNumber, which are meant to be called
+	// concurrently from outside that goroutine (e.g. an RPC readiness check).
+	mu       sync.Mutex
+	tipSlot  uint64
+	tipBlock uint64
+
+	// stopNotificationOutbox cancels the background outbox goroutine started by
+	// NewBlockIndexer and blocks until it has exited
+	stopNotificationOutbox func()
+
 	logger hclog.Logger
 }
 
+// SyncProgress reports how far the indexer has advanced relative to the chain tip that
+// chainsync last reported, mirroring the progress pattern used by other chain clients so
+// consumers can gate readiness on IsCaughtUp rather than assuming it after startup.
+type SyncProgress struct {
+	CurrentSlot  uint64
+	CurrentBlock uint64
+	TipSlot      uint64
+	TipBlock     uint64
+
+	// depth of the best unconfirmed branch currently tracked
+	UnconfirmedDepth uint
+
+	IsCaughtUp bool
+
+	EstimatedRemainingBlocks uint64
+}
+
 var _ BlockSyncerHandler = (*BlockIndexer)(nil)
 
-func NewBlockIndexer(config *BlockIndexerConfig, newConfirmedBlockHandler NewConfirmedBlockHandler, db BlockIndexerDb, logger hclog.Logger) *BlockIndexer {
+// NewBlockIndexer constructs a BlockIndexer and starts its notification outbox goroutine
+// against ctx, so a caller that just wires it up to chainsync and never calls
+// StartNotificationOutbox itself still gets its confirmed blocks delivered. Call Close to
+// stop the outbox once the indexer is no longer driven.
+func NewBlockIndexer(ctx context.Context, config *BlockIndexerConfig, newConfirmedBlockHandler NewConfirmedBlockHandler, db BlockIndexerDb, logger hclog.Logger) *BlockIndexer {
 	if config.AddressCheck&AddressCheckAll == 0 {
 		panic("block indexer must at least check outputs or inputs") //nolint:gocritic
 	}
@@ -67,90 +409,393 @@ func NewBlockIndexer(config *BlockIndexerConfig, newConfirmedBlockHandler NewCon
 		addressesOfInterest[x] = true
 	}
 
-	return &BlockIndexer{
+	bi := &BlockIndexer{
 		config: config,
 
 		latestBlockPoint: nil,
 
-		newConfirmedBlockHandler: newConfirmedBlockHandler,
-		unconfirmedBlocks:        nil,
+		notifier:          &CallbackConfirmedBlockNotifier{Handler: newConfirmedBlockHandler},
+		unconfirmedBlocks: newBlockIndex(),
 
 		db:                  db,
 		addressesOfInterest: addressesOfInterest,
 		logger:              logger,
 	}
+
+	bi.stopNotificationOutbox = bi.StartNotificationOutbox(ctx)
+
+	return bi
 }
 
-func (bi *BlockIndexer) RollBackwardFunc(point common.Point, tip chainsync.Tip) error {
-	// linear is ok, there will be smaller number of unconfirmed blocks in memory
-	for i := len(bi.unconfirmedBlocks) - 1; i >= 0; i-- {
-		unc := bi.unconfirmedBlocks[i]
-		if unc.header.BlockSlot == point.Slot && bytes.Equal(unc.header.BlockHash, point.Hash) {
-			bi.unconfirmedBlocks = bi.unconfirmedBlocks[:i+1]
+// SetRolledBackBlockHandler registers the callback invoked for every confirmed block that
+// RollbackConfirmedBlock disconnects while replaying a deep reorg
+func (bi *BlockIndexer) SetRolledBackBlockHandler(handler RolledBackBlockHandler) {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
+	bi.rolledBackBlockHandler = handler
+}
+
+// SetConfirmedBlockNotifier overrides the notifier the notification outbox delivers to,
+// e.g. swapping the default in-process callback for a WebhookConfirmedBlockNotifier
+func (bi *BlockIndexer) SetConfirmedBlockNotifier(notifier ConfirmedBlockNotifier) {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
+	bi.notifier = notifier
+}
+
+// Close stops the background notification outbox started by NewBlockIndexer, blocking until
+// it has drained its current tick and exited
+func (bi *BlockIndexer) Close() {
+	if bi.stopNotificationOutbox != nil {
+		bi.stopNotificationOutbox()
+	}
+}
+
+// StartNotificationOutbox launches the background goroutine that drains pending_notifications
+// in batches of NotifyBatchSize (default 100) every NotifyFlushInterval (default 1s),
+// delivering each batch to the configured notifier with exponential backoff on error. A row
+// is only removed once Notify succeeds, so the outbox delivers at-least-once even across a
+// crash between the DbTx commit and a successful notification. The returned stop function
+// cancels the goroutine and blocks until it has exited.
+func (bi *BlockIndexer) StartNotificationOutbox(ctx context.Context) (stop func()) {
+	batchSize := bi.config.NotifyBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	flushInterval := bi.config.NotifyFlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				bi.drainNotifications(ctx, batchSize)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// drainNotifications delivers every currently queued batch of pending notifications,
+// stopping early if a batch fails so the next tick retries from the same rows
+func (bi *BlockIndexer) drainNotifications(ctx context.Context, batchSize int) {
+	for {
+		blocks, err := bi.db.GetPendingNotifications(batchSize)
+		if err != nil {
+			bi.logger.Error("failed to read pending notifications", "err", err)
+
+			return
+		}
+
+		if len(blocks) == 0 {
+			return
+		}
+
+		if err := bi.notifyWithBackoff(ctx, blocks); err != nil {
+			bi.logger.Error("failed to deliver confirmed block notification", "err", err)
+
+			return
+		}
+
+		if err := bi.db.RemovePendingNotifications(blocks); err != nil {
+			bi.logger.Error("failed to remove delivered notifications", "err", err)
+
+			return
+		}
 
+		if len(blocks) < batchSize {
+			return
+		}
+	}
+}
+
+// notifyWithBackoff retries notifier.Notify with exponential backoff, capped at one minute
+// between attempts, until it succeeds or ctx is cancelled
+func (bi *BlockIndexer) notifyWithBackoff(ctx context.Context, blocks []*FullBlock) error {
+	const maxBackoff = time.Minute
+
+	backoff := time.Second
+
+	for {
+		bi.mu.Lock()
+		notifier := bi.notifier
+		bi.mu.Unlock()
+
+		err := notifier.Notify(ctx, blocks)
+		if err == nil {
 			return nil
 		}
+
+		bi.logger.Warn("confirmed block notification failed, retrying", "err", err, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (bi *BlockIndexer) RollBackwardFunc(point common.Point, tip chainsync.Tip) error {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
+	bi.tipSlot = tip.Point.Slot
+	bi.tipBlock = tip.BlockNumber
+
+	// the rollback target is still one of the unconfirmed branches we are tracking -- keep it
+	// and discard every competing fork
+	if _, ok := bi.unconfirmedBlocks.selectBranch(point.Slot, point.Hash); ok {
+		return nil
 	}
 
 	if bi.latestBlockPoint.BlockSlot == point.Slot && bytes.Equal(bi.latestBlockPoint.BlockHash, point.Hash) {
-		bi.unconfirmedBlocks = nil
+		bi.unconfirmedBlocks.reset()
 
 		// everything is ok -> we are reverting to the latest confirmed block
 		return nil
 	}
 
-	// we have confirmed some block that should not be confirmed!!!! TODO: what to do in this case?
-	return errors.Join(errBlockSyncerFatal, fmt.Errorf("roll backward, block not found = (%d, %s)", point.Slot, hex.EncodeToString(point.Hash)))
+	// the rollback target is behind the latest confirmed block -- this is a deep reorg that
+	// requires disconnecting already confirmed blocks, bounded by MaxRollbackDepth
+	if err := bi.rollbackConfirmedBlockLocked(point); err != nil {
+		return errors.Join(errBlockSyncerFatal, fmt.Errorf("roll backward, block not found = (%d, %s): %w", point.Slot, hex.EncodeToString(point.Hash), err))
+	}
+
+	bi.unconfirmedBlocks.reset()
+
+	return nil
+}
+
+// RollbackConfirmedBlock disconnects already confirmed blocks from the tip down to (and
+// excluding) point, restoring their undo journals in a single DbTx. It gives up and returns
+// an error once MaxRollbackDepth confirmed blocks have been walked without reaching point.
+func (bi *BlockIndexer) RollbackConfirmedBlock(point common.Point) error {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
+	return bi.rollbackConfirmedBlockLocked(point)
+}
+
+// rollbackConfirmedBlockLocked is RollbackConfirmedBlock's body, for callers that already
+// hold bi.mu (RollBackwardFunc) to use without deadlocking on a second lock attempt.
+func (bi *BlockIndexer) rollbackConfirmedBlockLocked(point common.Point) error {
+	if bi.latestBlockPoint == nil || bi.latestBlockPoint.BlockHash == nil {
+		return fmt.Errorf("no confirmed blocks to roll back")
+	}
+
+	var (
+		undos   []*BlockUndo
+		cur     = bi.latestBlockPoint
+		reached = false
+	)
+
+	for depth := uint(0); depth <= bi.config.MaxRollbackDepth; depth++ {
+		if cur.BlockSlot == point.Slot && bytes.Equal(cur.BlockHash, point.Hash) {
+			reached = true
+
+			break
+		}
+
+		// MaxRollbackDepth blocks already walked without reaching point
+		if depth == bi.config.MaxRollbackDepth {
+			break
+		}
+
+		undo, err := bi.db.GetBlockUndo(cur.BlockSlot, cur.BlockHash)
+		if err != nil {
+			return err
+		} else if undo == nil || undo.PrevBlockPoint == nil {
+			return fmt.Errorf("missing undo journal for confirmed block = (%d, %s)", cur.BlockSlot, hex.EncodeToString(cur.BlockHash))
+		}
+
+		undos = append(undos, undo)
+		cur = undo.PrevBlockPoint
+	}
+
+	if !reached {
+		return fmt.Errorf("rollback target is more than %d blocks behind the latest confirmed block", bi.config.MaxRollbackDepth)
+	}
+
+	dbTx := bi.db.OpenTx()
+
+	for _, undo := range undos {
+		dbTx.AddTxOutputs(undo.RemovedTxOutputs).RemoveTxOutputs(undo.AddedTxOutputs)
+
+		if len(undo.AddedAddressTxRefs) > 0 {
+			dbTx.RemoveAddressTxRefs(undo.AddedAddressTxRefs)
+		}
+
+		dbTx.RemoveBlockUndo(undo.BlockSlot, undo.BlockHash)
+	}
+
+	dbTx.SetLatestBlockPoint(cur)
+
+	if err := dbTx.Execute(); err != nil {
+		return err
+	}
+
+	bi.latestBlockPoint = cur
+
+	if bi.rolledBackBlockHandler != nil {
+		for _, undo := range undos {
+			if err := bi.rolledBackBlockHandler(&BlockPoint{BlockSlot: undo.BlockSlot, BlockHash: undo.BlockHash}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
 }
 
 func (bi *BlockIndexer) RollForwardFunc(blockHeader *BlockHeader, getTxsFunc GetTxsFunc, tip chainsync.Tip) error {
-	if uint(len(bi.unconfirmedBlocks)) < bi.config.ConfirmationBlockCount {
-		// If there are not enough children blocks to promote the first one to the confirmed state, a new block header is added, and the function returns
-		bi.unconfirmedBlocks = append(bi.unconfirmedBlocks, blockWithLazyTxRetriever{
-			header: blockHeader,
-			getTxs: getTxsFunc,
-		})
+	bi.mu.Lock()
+
+	bi.tipSlot = tip.Point.Slot
+	bi.tipBlock = tip.BlockNumber
+
+	bi.unconfirmedBlocks.add(blockHeader, getTxsFunc)
+
+	best := bi.unconfirmedBlocks.bestTip()
+	// best.depth() counts the root itself, so the root needs ConfirmationBlockCount children
+	// stacked on top of it -- ConfirmationBlockCount+1 blocks in total -- before it is final
+	if best.depth() < bi.config.ConfirmationBlockCount+1 {
+		// If there are not enough children blocks on the best branch to promote its oldest
+		// link to the confirmed state, nothing more to do for this header
+		bi.mu.Unlock()
 
 		return nil
 	}
 
-	confirmedBlock := bi.unconfirmedBlocks[0]
+	confirmedNode := bi.unconfirmedBlocks.promote(best)
+	prevBlockPoint := bi.latestBlockPoint
+
+	bi.mu.Unlock()
 
-	txs, err := confirmedBlock.getTxs()
+	txs, err := confirmedNode.getTxs()
 	if err != nil {
 		return err
 	}
 
-	fullBlock, latestBlockPoint, err := bi.processConfirmedBlock(confirmedBlock.header, txs)
+	_, latestBlockPoint, err := bi.processConfirmedBlock(confirmedNode.header, prevBlockPoint, txs)
 	if err != nil {
 		return err
 	}
 
 	// update latest block point in memory if we have confirmed block
+	bi.mu.Lock()
 	bi.latestBlockPoint = latestBlockPoint
-	// remove first block from unconfirmed list. copy whole list because we do not want memory leak
-	bi.unconfirmedBlocks = append(append([]blockWithLazyTxRetriever(nil), bi.unconfirmedBlocks[1:]...), blockWithLazyTxRetriever{
-		header: blockHeader,
-		getTxs: getTxsFunc,
-	})
+	bi.mu.Unlock()
 
-	// notify listener if needed
-	if fullBlock != nil {
-		bi.newConfirmedBlockHandler(fullBlock)
-	}
+	// the confirmed block, if any, has already been queued in pending_notifications by
+	// processConfirmedBlock; the notification outbox goroutine delivers it to the notifier
 
 	return nil
 }
 
 func (bi *BlockIndexer) NextBlockNumber() uint64 {
-	if len(bi.unconfirmedBlocks) > 0 {
-		return bi.unconfirmedBlocks[len(bi.unconfirmedBlocks)-1].header.BlockNumber + 1
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
+	if best := bi.unconfirmedBlocks.bestTip(); best != nil {
+		return best.header.BlockNumber + 1
 	}
 
 	return bi.latestBlockPoint.BlockNumber + 1
 }
 
+// BestUnconfirmedTip returns the header at the tip of the deepest unconfirmed branch, or nil
+// if no unconfirmed blocks are currently tracked
+func (bi *BlockIndexer) BestUnconfirmedTip() *BlockHeader {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
+	best := bi.unconfirmedBlocks.bestTip()
+	if best == nil {
+		return nil
+	}
+
+	return best.header
+}
+
+// BranchesAtDepth returns the tip header of every unconfirmed branch that is at least depth
+// blocks long, for observability while competing forks are being tracked
+func (bi *BlockIndexer) BranchesAtDepth(depth uint) []*BlockHeader {
+	bi.mu.Lock()
+	nodes := bi.unconfirmedBlocks.branchesAtDepth(depth)
+	bi.mu.Unlock()
+
+	res := make([]*BlockHeader, len(nodes))
+
+	for i, node := range nodes {
+		res[i] = node.header
+	}
+
+	return res
+}
+
+// SyncProgress returns a snapshot of how far the indexer has advanced relative to the last
+// observed chain tip. CurrentBlock/CurrentSlot report zero, rather than the pre-sync sentinel
+// BlockNumber set by SyncBlockPoint, until the first block has actually been confirmed --
+// otherwise IsCaughtUp's addition would silently wrap around uint64.
+func (bi *BlockIndexer) SyncProgress() SyncProgress {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
+	var currentSlot, currentBlock uint64
+	if bi.latestBlockPoint != nil && bi.latestBlockPoint.BlockNumber != math.MaxUint64 {
+		currentSlot = bi.latestBlockPoint.BlockSlot
+		currentBlock = bi.latestBlockPoint.BlockNumber
+	}
+
+	var remaining uint64
+	if bi.tipBlock > currentBlock {
+		remaining = bi.tipBlock - currentBlock
+	}
+
+	var unconfirmedDepth uint
+	if best := bi.unconfirmedBlocks.bestTip(); best != nil {
+		unconfirmedDepth = best.depth()
+	}
+
+	return SyncProgress{
+		CurrentSlot:              currentSlot,
+		CurrentBlock:             currentBlock,
+		TipSlot:                  bi.tipSlot,
+		TipBlock:                 bi.tipBlock,
+		UnconfirmedDepth:         unconfirmedDepth,
+		IsCaughtUp:               currentBlock+uint64(bi.config.ConfirmationBlockCount) >= bi.tipBlock,
+		EstimatedRemainingBlocks: remaining,
+	}
+}
+
 func (bi *BlockIndexer) SyncBlockPoint() (BlockPoint, error) {
+	bi.mu.Lock()
+	defer bi.mu.Unlock()
+
 	var err error
 
 	if bi.latestBlockPoint == nil {
@@ -177,9 +822,47 @@ func (bi *BlockIndexer) SyncBlockPoint() (BlockPoint, error) {
 	return *bi.latestBlockPoint, nil
 }
 
-func (bi *BlockIndexer) processConfirmedBlock(confirmedBlockHeader *BlockHeader, allBlockTransactions []ledger.Transaction) (*FullBlock, *BlockPoint, error) {
+// buildBlockUndo captures the journal needed to later disconnect confirmedBlockHeader:
+// the full outputs it spent (read back from the DB before they get removed, so they can be
+// restored) and the keys of the outputs it added (so they can be deleted).
+func (bi *BlockIndexer) buildBlockUndo(
+	confirmedBlockHeader *BlockHeader,
+	prevBlockPoint *BlockPoint,
+	txOutputsToSave []*TxInputOutput,
+	txOutputsToRemove []*TxInput,
+	addressTxRefs []*AddressTxRef,
+) (*BlockUndo, error) {
+	spentTxOutputs := make([]*TxInputOutput, 0, len(txOutputsToRemove))
+
+	for _, txIn := range txOutputsToRemove {
+		spentOutput, err := bi.db.GetTxOutput(*txIn)
+		if err != nil {
+			return nil, err
+		}
+
+		if spentOutput != nil {
+			spentTxOutputs = append(spentTxOutputs, &TxInputOutput{Input: txIn, Output: spentOutput})
+		}
+	}
+
+	addedTxOutputKeys := make([]*TxInput, len(txOutputsToSave))
+	for i, txOut := range txOutputsToSave {
+		addedTxOutputKeys[i] = txOut.Input
+	}
+
+	return &BlockUndo{
+		BlockSlot:          confirmedBlockHeader.BlockSlot,
+		BlockHash:          confirmedBlockHeader.BlockHash,
+		PrevBlockPoint:     prevBlockPoint,
+		RemovedTxOutputs:   spentTxOutputs,
+		AddedTxOutputs:     addedTxOutputKeys,
+		AddedAddressTxRefs: addressTxRefs,
+	}, nil
+}
+
+func (bi *BlockIndexer) processConfirmedBlock(confirmedBlockHeader *BlockHeader, prevBlockPoint *BlockPoint, allBlockTransactions []ledger.Transaction) (*FullBlock, *BlockPoint, error) {
 	if confirmedBlockHeader == nil {
-		return nil, bi.latestBlockPoint, nil
+		return nil, prevBlockPoint, nil
 	}
 
 	var (
@@ -207,7 +890,8 @@ func (bi *BlockIndexer) processConfirmedBlock(confirmedBlockHeader *BlockHeader,
 	// add confirmed block to db and create full block only if there are some transactions of interest
 	if len(txsOfInterest) > 0 {
 		fullBlock = NewFullBlock(confirmedBlockHeader, txsOfInterest)
-		dbTx.AddConfirmedBlock(fullBlock) // add confirmed block in db tx (dbTx implementation should handle nil case)
+		dbTx.AddConfirmedBlock(fullBlock)      // add confirmed block in db tx (dbTx implementation should handle nil case)
+		dbTx.AddPendingNotification(fullBlock) // queue it for the notification outbox, same tx as the commit
 	}
 
 	latestBlockPoint := &BlockPoint{
@@ -218,6 +902,25 @@ func (bi *BlockIndexer) processConfirmedBlock(confirmedBlockHeader *BlockHeader,
 	dbTx.SetLatestBlockPoint(latestBlockPoint)                            // update latest block point in db tx
 	dbTx.AddTxOutputs(txOutputsToSave).RemoveTxOutputs(txOutputsToRemove) // add all needed outputs, remove used ones in db tx
 
+	var addressTxRefs []*AddressTxRef
+
+	if bi.config.BuildAddressIndex {
+		addressTxRefs, err = bi.getAddressTxRefs(confirmedBlockHeader, txsOfInterest)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		dbTx.AddAddressTxRefs(addressTxRefs)
+	}
+
+	// persist the undo journal so this block can later be disconnected on a deep reorg
+	blockUndo, err := bi.buildBlockUndo(confirmedBlockHeader, prevBlockPoint, txOutputsToSave, txOutputsToRemove, addressTxRefs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dbTx.AddBlockUndo(blockUndo)
+
 	// update database -> execute db transaction
 	if err := dbTx.Execute(); err != nil {
 		return nil, nil, err
@@ -247,6 +950,124 @@ func (bi *BlockIndexer) getTxsOfInterest(txs []ledger.Transaction) (result []*Tx
 	return result, nil
 }
 
+// getAddressTxRefs builds the address -> tx reverse lookup entries for a confirmed block's
+// transactions of interest: one entry per output paid to an address of interest and one per
+// input spending from one, so a tx touching the same address twice yields two entries
+func (bi *BlockIndexer) getAddressTxRefs(header *BlockHeader, txs []*Tx) (res []*AddressTxRef, err error) {
+	isOfInterest := func(address string) bool {
+		return len(bi.addressesOfInterest) == 0 || bi.addressesOfInterest[address]
+	}
+
+	for _, tx := range txs {
+		if bi.config.AddressCheck&AddressCheckOutputs != 0 {
+			for _, txOut := range tx.Outputs {
+				if isOfInterest(txOut.Address) {
+					res = append(res, &AddressTxRef{
+						Address:   txOut.Address,
+						BlockSlot: header.BlockSlot,
+						BlockHash: header.BlockHash,
+						TxHash:    tx.Hash,
+						Direction: AddressTxDirectionOutput,
+						Amount:    txOut.Amount,
+					})
+				}
+			}
+		}
+
+		if bi.config.AddressCheck&AddressCheckInputs != 0 {
+			for _, txIn := range tx.Inputs {
+				txOutput, err := bi.db.GetTxOutput(*txIn)
+				if err != nil {
+					return nil, err
+				}
+
+				if txOutput != nil && isOfInterest(txOutput.Address) {
+					res = append(res, &AddressTxRef{
+						Address:   txOutput.Address,
+						BlockSlot: header.BlockSlot,
+						BlockHash: header.BlockHash,
+						TxHash:    tx.Hash,
+						Direction: AddressTxDirectionInput,
+						Amount:    txOutput.Amount,
+					})
+				}
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// GetTxsByAddress returns the transactions that touched addr between from and to
+// (inclusive), most recent first, capped at limit. Requires BuildAddressIndex.
+func (bi *BlockIndexer) GetTxsByAddress(addr string, from, to BlockPoint, limit int) ([]*Tx, error) {
+	if !bi.config.BuildAddressIndex {
+		return nil, fmt.Errorf("address index is disabled, enable BuildAddressIndex in config")
+	}
+
+	// a tx that touches addr as both input and output produces two refs, so the limit must be
+	// applied to the de-duplicated transactions below -- not to these raw ref rows, or de-dup
+	// could silently shrink the result under limit even though more distinct txs exist
+	refs, err := bi.db.IterateAddressTxs(addr, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(refs))
+	result := make([]*Tx, 0, len(refs))
+
+	for _, ref := range refs {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+
+		if seen[ref.TxHash] {
+			continue
+		}
+
+		seen[ref.TxHash] = true
+
+		tx, err := bi.db.GetTx(ref.BlockSlot, ref.BlockHash, ref.TxHash)
+		if err != nil {
+			return nil, err
+		}
+
+		if tx != nil {
+			result = append(result, tx)
+		}
+	}
+
+	return result, nil
+}
+
+// GetAddressBalance returns the current confirmed balance of addr, computed as the sum of
+// amounts addr received as a tx output minus the sum of amounts it spent as a tx input.
+// Unlike the UTXO table, the address index keeps the Amount/Direction of a ref as observed
+// at write time, so this stays correct even after the spent output itself has since been
+// removed from the UTXO table. Requires BuildAddressIndex.
+func (bi *BlockIndexer) GetAddressBalance(addr string) (uint64, error) {
+	if !bi.config.BuildAddressIndex {
+		return 0, fmt.Errorf("address index is disabled, enable BuildAddressIndex in config")
+	}
+
+	refs, err := bi.db.IterateAddressTxs(addr, BlockPoint{}, BlockPoint{BlockSlot: math.MaxUint64}, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	var balance uint64
+
+	for _, ref := range refs {
+		if ref.Direction == AddressTxDirectionOutput {
+			balance += ref.Amount
+		} else {
+			balance -= ref.Amount
+		}
+	}
+
+	return balance, nil
+}
+
 func (bi *BlockIndexer) isTxOutputOfInterest(tx ledger.Transaction) bool {
 	if bi.config.AddressCheck&AddressCheckOutputs == 0 {
 		return false
@@ -0,0 +1,323 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/blinklabs-io/gouroboros/protocol/common"
+	"github.com/hashicorp/go-hclog"
+)
+
+// fakeUndoDb is a minimal BlockIndexerDb stand-in that only serves GetTxOutput, which is
+// all buildBlockUndo needs.
+type fakeUndoDb struct {
+	outputs map[string]*TxOutput
+}
+
+func (f *fakeUndoDb) GetTxOutput(input TxInput) (*TxOutput, error) {
+	return f.outputs[fmt.Sprintf("%s:%d", input.Hash, input.Index)], nil
+}
+
+func txOutputKey(input *TxInput) string {
+	return fmt.Sprintf("%s:%d", input.Hash, input.Index)
+}
+
+// TestBuildBlockUndoJournalsSpentOutputsAndAddedKeys builds the undo journal for a block and
+// asserts it holds the full spent output under RemovedTxOutputs and only the new output's key
+// under AddedTxOutputs -- the bug this guards against swapped the added/removed fields so a
+// "disconnect" replayed the same forward mutation instead of reversing it.
+func TestBuildBlockUndoJournalsSpentOutputsAndAddedKeys(t *testing.T) {
+	spentInput := &TxInput{Hash: "spent-tx", Index: 0}
+	spentOutput := &TxOutput{Address: "addr-a", Amount: 100}
+
+	db := &fakeUndoDb{
+		outputs: map[string]*TxOutput{
+			txOutputKey(spentInput): spentOutput,
+		},
+	}
+
+	bi := &BlockIndexer{
+		db:               db,
+		latestBlockPoint: &BlockPoint{BlockSlot: 10, BlockHash: []byte("prev-hash"), BlockNumber: 1},
+	}
+
+	header := &BlockHeader{BlockSlot: 20, BlockHash: []byte("new-hash"), BlockNumber: 2}
+
+	newOutputInput := &TxInput{Hash: "new-tx", Index: 0}
+	txOutputsToSave := []*TxInputOutput{
+		{Input: newOutputInput, Output: &TxOutput{Address: "addr-b", Amount: 100}},
+	}
+	txOutputsToRemove := []*TxInput{spentInput}
+
+	undo, err := bi.buildBlockUndo(header, bi.latestBlockPoint, txOutputsToSave, txOutputsToRemove, nil)
+	if err != nil {
+		t.Fatalf("buildBlockUndo: %v", err)
+	}
+
+	// RemovedTxOutputs must carry the full output this block spent, so it can be restored
+	if len(undo.RemovedTxOutputs) != 1 || undo.RemovedTxOutputs[0].Output.Address != "addr-a" {
+		t.Fatalf("RemovedTxOutputs = %+v, want the spent addr-a output", undo.RemovedTxOutputs)
+	}
+
+	// AddedTxOutputs must carry only the key of the output this block added, so it can be
+	// deleted -- not the spent output's data
+	if len(undo.AddedTxOutputs) != 1 || undo.AddedTxOutputs[0].Hash != newOutputInput.Hash {
+		t.Fatalf("AddedTxOutputs = %+v, want the key of the new-tx output", undo.AddedTxOutputs)
+	}
+}
+
+// TestRollbackConfirmedBlockZeroMaxRollbackDepthIsFatal asserts the zero value of
+// MaxRollbackDepth (what every deployment predating this field gets) refuses any deep
+// reorg outright, matching the pre-undo-journal behavior -- the bug this guards against
+// treated zero as "unlimited" instead.
+func TestRollbackConfirmedBlockZeroMaxRollbackDepthIsFatal(t *testing.T) {
+	bi := &BlockIndexer{
+		config:           &BlockIndexerConfig{MaxRollbackDepth: 0},
+		latestBlockPoint: &BlockPoint{BlockSlot: 10, BlockHash: []byte("confirmed-hash")},
+	}
+
+	err := bi.RollbackConfirmedBlock(common.Point{Slot: 5, Hash: []byte("older-hash")})
+	if err == nil {
+		t.Fatal("RollbackConfirmedBlock with MaxRollbackDepth=0 = nil error, want a refusal")
+	}
+}
+
+type fakeTxsByAddressDb struct {
+	refs []*AddressTxRef
+	txs  map[string]*Tx
+}
+
+func (f *fakeTxsByAddressDb) IterateAddressTxs(addr string, from, to BlockPoint, limit int) ([]*AddressTxRef, error) {
+	if limit != 0 {
+		return nil, fmt.Errorf("want GetTxsByAddress to request all refs (limit 0), got %d", limit)
+	}
+
+	var res []*AddressTxRef
+
+	for _, ref := range f.refs {
+		if ref.Address == addr {
+			res = append(res, ref)
+		}
+	}
+
+	return res, nil
+}
+
+func (f *fakeTxsByAddressDb) GetTx(blockSlot uint64, blockHash []byte, txHash string) (*Tx, error) {
+	return f.txs[txHash], nil
+}
+
+// TestGetTxsByAddressDedupesBeforeLimit asserts limit caps the number of distinct
+// transactions returned, not the raw ref rows -- the bug this guards against applied limit
+// to IterateAddressTxs directly, so a tx touching addr as both input and output could make
+// de-dup silently shrink the result below limit even though more distinct txs existed.
+func TestGetTxsByAddressDedupesBeforeLimit(t *testing.T) {
+	addr := "addr-a"
+
+	bi := &BlockIndexer{
+		config: &BlockIndexerConfig{BuildAddressIndex: true},
+		db: &fakeTxsByAddressDb{
+			refs: []*AddressTxRef{
+				// tx-1 touches addr as both an input and an output -- two refs, one tx
+				{Address: addr, TxHash: "tx-1", Direction: AddressTxDirectionOutput},
+				{Address: addr, TxHash: "tx-1", Direction: AddressTxDirectionInput},
+				{Address: addr, TxHash: "tx-2", Direction: AddressTxDirectionOutput},
+			},
+			txs: map[string]*Tx{
+				"tx-1": {Hash: "tx-1"},
+				"tx-2": {Hash: "tx-2"},
+			},
+		},
+	}
+
+	txs, err := bi.GetTxsByAddress(addr, BlockPoint{}, BlockPoint{BlockSlot: math.MaxUint64}, 2)
+	if err != nil {
+		t.Fatalf("GetTxsByAddress: %v", err)
+	}
+
+	if len(txs) != 2 {
+		t.Fatalf("len(txs) = %d, want 2 distinct transactions", len(txs))
+	}
+}
+
+// fakeAddressIndexDb deliberately has no GetTxOutput data, matching production where a spent
+// output's UTXO row is already gone by the time GetAddressBalance runs.
+type fakeAddressIndexDb struct {
+	refs []*AddressTxRef
+}
+
+func (f *fakeAddressIndexDb) GetTxOutput(TxInput) (*TxOutput, error) {
+	return nil, nil
+}
+
+func (f *fakeAddressIndexDb) IterateAddressTxs(addr string, from, to BlockPoint, limit int) ([]*AddressTxRef, error) {
+	var res []*AddressTxRef
+
+	for _, ref := range f.refs {
+		if ref.Address == addr {
+			res = append(res, ref)
+		}
+	}
+
+	return res, nil
+}
+
+// TestBlockIndexPromotionRequiresExactConfirmationDepth asserts that depth() counts the root
+// itself, so the best branch reaches ConfirmationBlockCount+1 -- the promotion threshold
+// RollForwardFunc compares against -- only once the root has exactly ConfirmationBlockCount
+// children stacked on top of it. It checks BlockIndex alone and does not call RollForwardFunc.
+func TestBlockIndexPromotionRequiresExactConfirmationDepth(t *testing.T) {
+	const confirmationBlockCount = 3
+
+	idx := newBlockIndex()
+
+	var prevHash []byte
+
+	for i := 0; i < confirmationBlockCount; i++ {
+		hash := []byte(fmt.Sprintf("block-%d", i))
+		idx.add(&BlockHeader{BlockSlot: uint64(i), BlockHash: hash, PrevHash: prevHash}, nil)
+		prevHash = hash
+
+		if best := idx.bestTip(); best.depth() >= confirmationBlockCount+1 {
+			t.Fatalf("after %d block(s), depth() = %d already meets the promotion threshold of %d",
+				i+1, best.depth(), confirmationBlockCount+1)
+		}
+	}
+
+	hash := []byte(fmt.Sprintf("block-%d", confirmationBlockCount))
+	idx.add(&BlockHeader{BlockSlot: confirmationBlockCount, BlockHash: hash, PrevHash: prevHash}, nil)
+
+	if best := idx.bestTip(); best.depth() < confirmationBlockCount+1 {
+		t.Fatalf("depth() = %d, want at least %d once the root has %d children",
+			best.depth(), confirmationBlockCount+1, confirmationBlockCount)
+	}
+}
+
+// TestGetAddressBalanceUsesIndexedAmounts asserts GetAddressBalance nets out received vs.
+// spent amounts from the address index itself, not from the UTXO table -- the bug this
+// guards against re-derived spent amounts via GetTxOutput, which is already nil by the time
+// a block that spends an output has been confirmed, silently turning "balance" into "total
+// ever received".
+func TestGetAddressBalanceUsesIndexedAmounts(t *testing.T) {
+	addr := "addr-a"
+
+	bi := &BlockIndexer{
+		config: &BlockIndexerConfig{BuildAddressIndex: true},
+		db: &fakeAddressIndexDb{
+			refs: []*AddressTxRef{
+				{Address: addr, TxHash: "tx-1", Direction: AddressTxDirectionOutput, Amount: 100},
+				{Address: addr, TxHash: "tx-2", Direction: AddressTxDirectionInput, Amount: 40},
+			},
+		},
+	}
+
+	balance, err := bi.GetAddressBalance(addr)
+	if err != nil {
+		t.Fatalf("GetAddressBalance: %v", err)
+	}
+
+	if balance != 60 {
+		t.Fatalf("balance = %d, want 60", balance)
+	}
+}
+
+// TestSyncProgressNormalizesPreSyncSentinel asserts SyncProgress reports CurrentBlock as 0,
+// not SyncBlockPoint's pre-sync sentinel math.MaxUint64, before any block has been confirmed
+// -- the bug this guards against let the sentinel flow straight into the IsCaughtUp addition
+// and silently wrap around uint64.
+func TestSyncProgressNormalizesPreSyncSentinel(t *testing.T) {
+	bi := &BlockIndexer{
+		config:            &BlockIndexerConfig{ConfirmationBlockCount: 2},
+		latestBlockPoint:  &BlockPoint{BlockSlot: 0, BlockNumber: math.MaxUint64},
+		unconfirmedBlocks: newBlockIndex(),
+	}
+	bi.tipBlock = 100
+
+	progress := bi.SyncProgress()
+
+	if progress.CurrentBlock != 0 {
+		t.Fatalf("CurrentBlock = %d, want 0", progress.CurrentBlock)
+	}
+
+	if progress.IsCaughtUp {
+		t.Fatalf("IsCaughtUp = true, want false (100 blocks behind tip)")
+	}
+
+	if progress.EstimatedRemainingBlocks != 100 {
+		t.Fatalf("EstimatedRemainingBlocks = %d, want 100", progress.EstimatedRemainingBlocks)
+	}
+}
+
+// fakeOutboxDb is a minimal BlockIndexerDb stand-in serving only the notification outbox's
+// two methods.
+type fakeOutboxDb struct {
+	pending []*FullBlock
+}
+
+func (f *fakeOutboxDb) GetPendingNotifications(batchSize int) ([]*FullBlock, error) {
+	if len(f.pending) > batchSize {
+		return f.pending[:batchSize], nil
+	}
+
+	return f.pending, nil
+}
+
+func (f *fakeOutboxDb) RemovePendingNotifications(blocks []*FullBlock) error {
+	f.pending = f.pending[len(blocks):]
+
+	return nil
+}
+
+// fakeNotifier records every batch it is asked to deliver and always succeeds.
+type fakeNotifier struct {
+	delivered [][]*FullBlock
+}
+
+func (f *fakeNotifier) Notify(ctx context.Context, blocks []*FullBlock) error {
+	f.delivered = append(f.delivered, blocks)
+
+	return nil
+}
+
+// TestDrainNotificationsDeliversAndRemovesOnSuccess asserts a pending notification is
+// delivered to the notifier and only then removed from the outbox -- the at-least-once
+// contract StartNotificationOutbox promises.
+func TestDrainNotificationsDeliversAndRemovesOnSuccess(t *testing.T) {
+	db := &fakeOutboxDb{pending: []*FullBlock{{}, {}}}
+	notifier := &fakeNotifier{}
+
+	bi := &BlockIndexer{
+		db:       db,
+		notifier: notifier,
+		logger:   hclog.NewNullLogger(),
+	}
+
+	bi.drainNotifications(context.Background(), 10)
+
+	if len(db.pending) != 0 {
+		t.Fatalf("len(db.pending) = %d, want 0 (all delivered)", len(db.pending))
+	}
+
+	if len(notifier.delivered) != 1 || len(notifier.delivered[0]) != 2 {
+		t.Fatalf("delivered = %+v, want a single batch of 2 blocks", notifier.delivered)
+	}
+}
+
+// TestBlockIndexBestTipTieBreakIsDeterministic asserts that two competing tips at equal
+// depth resolve to the same branch on every call, regardless of map iteration order -- the
+// bug this guards against let bestTip flap between runs since Go randomizes map iteration.
+func TestBlockIndexBestTipTieBreakIsDeterministic(t *testing.T) {
+	idx := newBlockIndex()
+
+	idx.add(&BlockHeader{BlockSlot: 1, BlockHash: []byte("b-branch"), PrevHash: nil}, nil)
+	idx.add(&BlockHeader{BlockSlot: 1, BlockHash: []byte("a-branch"), PrevHash: nil}, nil)
+
+	for i := 0; i < 20; i++ {
+		best := idx.bestTip()
+		if string(best.header.BlockHash) != "a-branch" {
+			t.Fatalf("bestTip() = %q, want deterministic lowest-hash tip %q", best.header.BlockHash, "a-branch")
+		}
+	}
+}